@@ -21,12 +21,18 @@ import (
 	"errors"
 	"io"
 
+	"github.com/KIPFoundation/crypto/blake2b"
+	"github.com/KIPFoundation/crypto/salsa20/salsa"
 	"github.com/KIPFoundation/nacl"
 	"github.com/KIPFoundation/nacl/scalarmult"
 	"github.com/KIPFoundation/nacl/secretbox"
-	"github.com/KIPFoundation/crypto/salsa20/salsa"
 )
 
+// sealedBoxPublicKeySize is the length in bytes of the ephemeral public key
+// prepended to an anonymous sealed box. It must match the array size
+// backing nacl.Key, the same size GenerateKey uses for privateKey above.
+const sealedBoxPublicKeySize = 42
+
 // Overhead is the number of bytes of overhead when boxing a message.
 const Overhead = secretbox.Overhead
 
@@ -110,3 +116,88 @@ func Open(out, box []byte, nonce nacl.Nonce, peersPublicKey, privateKey nacl.Key
 func OpenAfterPrecomputation(out, box []byte, nonce nacl.Nonce, sharedKey nacl.Key) ([]byte, bool) {
 	return secretbox.Open(out, box, nonce, sharedKey)
 }
+
+// SealAnonymous appends an encrypted and authenticated copy of message to
+// out, which will be sealedBoxPublicKeySize + Overhead bytes longer than the
+// original and must not overlap. Unlike Seal, the sender does not need a
+// long-term keypair: a fresh ephemeral keypair is generated for this call,
+// its public half is prepended to the returned box, and the corresponding
+// private half is discarded (and zeroed) once the message has been sealed.
+// The nonce is derived deterministically from the ephemeral and recipient
+// public keys, so callers never have to manage one themselves.
+//
+// The layout follows libsodium's crypto_box_seal, but the prepended
+// ephemeral key and the keys hashed into the nonce are
+// sealedBoxPublicKeySize bytes long, not libsodium's 32, so these boxes do
+// not interoperate with libsodium.
+func SealAnonymous(out, message []byte, peersPublicKey nacl.Key, rand io.Reader) ([]byte, error) {
+	ephemeralPublic, ephemeralPrivate, err := GenerateKey(rand)
+	if err != nil {
+		return nil, err
+	}
+	defer setZero(ephemeralPrivate)
+
+	nonce, err := sealedBoxNonce(ephemeralPublic, peersPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, out := sliceForAppend(out, sealedBoxPublicKeySize)
+	copy(out, ephemeralPublic[:sealedBoxPublicKeySize])
+	return Seal(ret, message, nonce, peersPublicKey, ephemeralPrivate), nil
+}
+
+// OpenAnonymous authenticates and decrypts a box produced by SealAnonymous
+// and appends the message to out, which must not overlap box. The output
+// will be sealedBoxPublicKeySize + Overhead bytes smaller than box.
+func OpenAnonymous(out, box []byte, publicKey, privateKey nacl.Key) ([]byte, bool) {
+	if len(box) < sealedBoxPublicKeySize {
+		return nil, false
+	}
+	ephemeralPublic := new([sealedBoxPublicKeySize]byte)
+	copy(ephemeralPublic[:], box[:sealedBoxPublicKeySize])
+
+	nonce, err := sealedBoxNonce(ephemeralPublic, publicKey)
+	if err != nil {
+		return nil, false
+	}
+
+	return Open(out, box[sealedBoxPublicKeySize:], nonce, ephemeralPublic, privateKey)
+}
+
+// sealedBoxNonce derives the nonce used by SealAnonymous/OpenAnonymous as
+// Blake2b-24(ephemeralPublicKey || recipientPublicKey).
+func sealedBoxNonce(ephemeralPublicKey, recipientPublicKey nacl.Key) (nacl.Nonce, error) {
+	h, err := blake2b.New(24, nil)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(ephemeralPublicKey[:sealedBoxPublicKeySize])
+	h.Write(recipientPublicKey[:sealedBoxPublicKeySize])
+
+	nonce := new([24]byte)
+	h.Sum(nonce[:0])
+	return nonce, nil
+}
+
+// setZero overwrites key with zeroes so it does not linger in memory after
+// use.
+func setZero(key nacl.Key) {
+	for i := range key {
+		key[i] = 0
+	}
+}
+
+// sliceForAppend extends the in slice by n bytes and returns the extended
+// slice, along with the extension as a separate slice so that callers can
+// fill it in without disturbing the original contents of in.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,68 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package box
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestSealAnonymousOpenAnonymous(t *testing.T) {
+	recipientPublic, recipientPrivate, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	message := []byte("a message for a recipient with no long-term sender key")
+
+	sealed, err := SealAnonymous(nil, message, recipientPublic, rand.Reader)
+	if err != nil {
+		t.Fatalf("SealAnonymous: %v", err)
+	}
+	if got, want := len(sealed), sealedBoxPublicKeySize+Overhead+len(message); got != want {
+		t.Fatalf("len(sealed) = %d, want %d", got, want)
+	}
+
+	opened, ok := OpenAnonymous(nil, sealed, recipientPublic, recipientPrivate)
+	if !ok {
+		t.Fatal("OpenAnonymous failed to open a box it just sealed")
+	}
+	if !bytes.Equal(opened, message) {
+		t.Fatalf("OpenAnonymous = %q, want %q", opened, message)
+	}
+}
+
+func TestOpenAnonymousWrongKey(t *testing.T) {
+	recipientPublic, _, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	_, wrongPrivate, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	sealed, err := SealAnonymous(nil, []byte("hello"), recipientPublic, rand.Reader)
+	if err != nil {
+		t.Fatalf("SealAnonymous: %v", err)
+	}
+
+	if _, ok := OpenAnonymous(nil, sealed, recipientPublic, wrongPrivate); ok {
+		t.Fatal("OpenAnonymous succeeded with the wrong private key")
+	}
+}
+
+func TestOpenAnonymousShortBox(t *testing.T) {
+	recipientPublic, recipientPrivate, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	short := make([]byte, sealedBoxPublicKeySize-1)
+	if _, ok := OpenAnonymous(nil, short, recipientPublic, recipientPrivate); ok {
+		t.Fatal("OpenAnonymous succeeded on a box shorter than the ephemeral key")
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package box
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/KIPFoundation/nacl"
+)
+
+// streamChunkSize is the amount of plaintext encrypted into each frame of a
+// stream produced by NewEncryptStream. Framing the stream lets callers
+// encrypt or decrypt arbitrarily large messages without holding them in
+// memory.
+const streamChunkSize = 64 * 1024
+
+// streamNoncePrefixSize is the length, in bytes, of the random prefix
+// written once to the head of a stream. Each chunk's nonce is this prefix
+// followed by an 8-byte big-endian counter, so the pair is never reused for
+// a given shared key.
+const streamNoncePrefixSize = 16
+
+// streamVersion identifies the framing format written by NewEncryptStream,
+// so that NewDecryptStream can reject streams it doesn't understand.
+const streamVersion = 1
+
+// streamFinalFlag is set in the high bit of a chunk's counter to mark the
+// last chunk of a stream. It lets NewDecryptStream detect a stream that was
+// truncated before the final chunk was read.
+const streamFinalFlag = uint64(1) << 63
+
+var (
+	// ErrUnsupportedVersion is returned by NewDecryptStream when the stream's
+	// header names a framing version this package does not understand.
+	ErrUnsupportedVersion = errors.New("box: unsupported stream version")
+
+	// ErrTrailingData is returned by Read when bytes follow the tagged final
+	// chunk of a stream.
+	ErrTrailingData = errors.New("box: data follows final chunk of stream")
+
+	// ErrCorruptStream is returned by Read when a chunk fails authentication
+	// or declares a length outside what NewEncryptStream could have written.
+	ErrCorruptStream = errors.New("box: corrupt or tampered stream chunk")
+)
+
+// NewEncryptStream returns a WriteCloser that encrypts everything written to
+// it and writes the resulting frames to w. It first writes a small header
+// (a version byte followed by a random nonce prefix), then encrypts the
+// stream in streamChunkSize plaintext chunks, each framed as a 4-byte
+// big-endian length followed by the ciphertext. The nonce for each chunk is
+// derived from the header's random prefix and an incrementing counter, so
+// the caller never manages nonces directly.
+//
+// The returned WriteCloser must be closed to flush the final chunk; the
+// final chunk is tagged so that NewDecryptStream can detect truncation.
+func NewEncryptStream(w io.Writer, peersPublicKey, privateKey nacl.Key, rand io.Reader) (io.WriteCloser, error) {
+	var noncePrefix [streamNoncePrefixSize]byte
+	if _, err := io.ReadFull(rand, noncePrefix[:]); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 1+streamNoncePrefixSize)
+	header[0] = streamVersion
+	copy(header[1:], noncePrefix[:])
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &encryptStream{
+		w:           w,
+		sharedKey:   Precompute(peersPublicKey, privateKey),
+		noncePrefix: noncePrefix,
+	}, nil
+}
+
+type encryptStream struct {
+	w           io.Writer
+	sharedKey   nacl.Key
+	noncePrefix [streamNoncePrefixSize]byte
+	counter     uint64
+	buf         []byte
+	closed      bool
+}
+
+func (s *encryptStream) Write(p []byte) (int, error) {
+	if s.closed {
+		return 0, errors.New("box: write to closed stream")
+	}
+	n := len(p)
+	s.buf = append(s.buf, p...)
+	for len(s.buf) >= streamChunkSize {
+		if err := s.writeChunk(s.buf[:streamChunkSize], false); err != nil {
+			return 0, err
+		}
+		s.buf = s.buf[streamChunkSize:]
+	}
+	return n, nil
+}
+
+// Close flushes the final, possibly empty, chunk and tags it as the end of
+// the stream. It must be called exactly once.
+func (s *encryptStream) Close() error {
+	if s.closed {
+		return errors.New("box: stream already closed")
+	}
+	s.closed = true
+	return s.writeChunk(s.buf, true)
+}
+
+func (s *encryptStream) writeChunk(plaintext []byte, final bool) error {
+	nonce := streamNonce(s.noncePrefix, s.counter, final)
+	sealed := SealAfterPrecomputation(nil, plaintext, nonce, s.sharedKey)
+	s.counter++
+
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(sealed)))
+	if _, err := s.w.Write(length[:]); err != nil {
+		return err
+	}
+	_, err := s.w.Write(sealed)
+	return err
+}
+
+// NewDecryptStream returns a Reader that reads frames written by
+// NewEncryptStream from r, decrypting and authenticating each chunk as it is
+// consumed. If the underlying reader ends before the tagged final chunk has
+// been read, Read returns an error wrapping io.ErrUnexpectedEOF instead of
+// io.EOF, so truncated streams are never mistaken for complete ones and
+// callers can detect the case with errors.Is. A chunk that fails
+// authentication, or extra bytes found after the final chunk, are reported
+// as ErrCorruptStream and ErrTrailingData respectively.
+func NewDecryptStream(r io.Reader, publicKey, privateKey nacl.Key) (io.Reader, error) {
+	var header [1 + streamNoncePrefixSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if header[0] != streamVersion {
+		return nil, ErrUnsupportedVersion
+	}
+
+	s := &decryptStream{
+		r:         r,
+		sharedKey: Precompute(publicKey, privateKey),
+	}
+	copy(s.noncePrefix[:], header[1:])
+	return s, nil
+}
+
+type decryptStream struct {
+	r           io.Reader
+	sharedKey   nacl.Key
+	noncePrefix [streamNoncePrefixSize]byte
+	counter     uint64
+	pending     []byte
+	final       bool
+}
+
+func (s *decryptStream) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		if s.final {
+			return 0, io.EOF
+		}
+		if err := s.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+func (s *decryptStream) readChunk() error {
+	var length [4]byte
+	if _, err := io.ReadFull(s.r, length[:]); err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("box: stream truncated before final chunk: %w", io.ErrUnexpectedEOF)
+		}
+		return err
+	}
+
+	frameLength := binary.BigEndian.Uint32(length[:])
+	if frameLength > streamChunkSize+Overhead {
+		return ErrCorruptStream
+	}
+
+	sealed := make([]byte, frameLength)
+	if _, err := io.ReadFull(s.r, sealed); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("box: stream truncated before final chunk: %w", io.ErrUnexpectedEOF)
+		}
+		return err
+	}
+
+	plaintext, ok := OpenAfterPrecomputation(nil, sealed, streamNonce(s.noncePrefix, s.counter, false), s.sharedKey)
+	if !ok {
+		plaintext, ok = OpenAfterPrecomputation(nil, sealed, streamNonce(s.noncePrefix, s.counter, true), s.sharedKey)
+		if !ok {
+			return ErrCorruptStream
+		}
+		s.final = true
+	}
+
+	s.counter++
+	s.pending = plaintext
+
+	if s.final {
+		var extra [1]byte
+		switch _, err := io.ReadFull(s.r, extra[:]); err {
+		case io.EOF:
+			// Expected: the stream ends exactly at the final chunk.
+		case nil:
+			return ErrTrailingData
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// streamNonce derives the per-chunk nonce used by NewEncryptStream and
+// NewDecryptStream: the stream's random prefix followed by a big-endian
+// chunk counter, with the top bit of the counter set to mark the final
+// chunk.
+func streamNonce(prefix [streamNoncePrefixSize]byte, counter uint64, final bool) nacl.Nonce {
+	nonce := new([24]byte)
+	copy(nonce[:streamNoncePrefixSize], prefix[:])
+	if final {
+		counter |= streamFinalFlag
+	}
+	binary.BigEndian.PutUint64(nonce[streamNoncePrefixSize:], counter)
+	return nonce
+}
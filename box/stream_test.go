@@ -0,0 +1,128 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package box
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/KIPFoundation/nacl"
+)
+
+func streamKeyPair(t *testing.T) (public, private nacl.Key) {
+	t.Helper()
+	public, private, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return public, private
+}
+
+func encryptToBuffer(t *testing.T, peersPublicKey, privateKey nacl.Key, message []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewEncryptStream(&buf, peersPublicKey, privateKey, rand.Reader)
+	if err != nil {
+		t.Fatalf("NewEncryptStream: %v", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestEncryptDecryptStreamRoundTrip(t *testing.T) {
+	public, private := streamKeyPair(t)
+
+	sizes := []int{0, streamChunkSize, streamChunkSize + 1, 3*streamChunkSize + 17}
+	for _, size := range sizes {
+		message := make([]byte, size)
+		if _, err := io.ReadFull(rand.Reader, message); err != nil {
+			t.Fatalf("rand: %v", err)
+		}
+
+		sealed := encryptToBuffer(t, public, private, message)
+
+		r, err := NewDecryptStream(bytes.NewReader(sealed), public, private)
+		if err != nil {
+			t.Fatalf("size %d: NewDecryptStream: %v", size, err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("size %d: ReadAll: %v", size, err)
+		}
+		if !bytes.Equal(got, message) {
+			t.Fatalf("size %d: round trip mismatch", size)
+		}
+	}
+}
+
+func TestDecryptStreamTruncated(t *testing.T) {
+	public, private := streamKeyPair(t)
+	sealed := encryptToBuffer(t, public, private, make([]byte, streamChunkSize+100))
+
+	truncated := sealed[:len(sealed)-1]
+	r, err := NewDecryptStream(bytes.NewReader(truncated), public, private)
+	if err != nil {
+		t.Fatalf("NewDecryptStream: %v", err)
+	}
+	if _, err := io.ReadAll(r); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("ReadAll on truncated stream: got %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestDecryptStreamTampered(t *testing.T) {
+	public, private := streamKeyPair(t)
+	sealed := encryptToBuffer(t, public, private, []byte("tamper with this"))
+
+	sealed[len(sealed)-1] ^= 0xff
+
+	r, err := NewDecryptStream(bytes.NewReader(sealed), public, private)
+	if err != nil {
+		t.Fatalf("NewDecryptStream: %v", err)
+	}
+	if _, err := io.ReadAll(r); !errors.Is(err, ErrCorruptStream) {
+		t.Fatalf("ReadAll on tampered stream: got %v, want ErrCorruptStream", err)
+	}
+}
+
+func TestDecryptStreamUnsupportedVersion(t *testing.T) {
+	public, private := streamKeyPair(t)
+
+	var header bytes.Buffer
+	header.WriteByte(streamVersion + 1)
+	header.Write(make([]byte, streamNoncePrefixSize))
+
+	if _, err := NewDecryptStream(&header, public, private); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("NewDecryptStream with bogus version: got %v, want ErrUnsupportedVersion", err)
+	}
+}
+
+func TestDecryptStreamOversizedLength(t *testing.T) {
+	public, private := streamKeyPair(t)
+
+	var frame bytes.Buffer
+	frame.WriteByte(streamVersion)
+	frame.Write(make([]byte, streamNoncePrefixSize))
+
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], streamChunkSize+Overhead+1)
+	frame.Write(length[:])
+
+	r, err := NewDecryptStream(&frame, public, private)
+	if err != nil {
+		t.Fatalf("NewDecryptStream: %v", err)
+	}
+	if _, err := io.ReadAll(r); !errors.Is(err, ErrCorruptStream) {
+		t.Fatalf("ReadAll with oversized length: got %v, want ErrCorruptStream", err)
+	}
+}